@@ -2,50 +2,167 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
-	"github.com/chromedp/chromedp"
-	"github.com/joho/godotenv"
 	"log"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/joho/godotenv"
 )
 
-// Post represents a single post from the Telegram channel.
+// Post represents the structured data extracted from a single Telegram post.
 type Post struct {
-	Text string `json:"text"`
+	MessageID     string   `json:"message_id"`
+	Timestamp     string   `json:"timestamp"`
+	Author        string   `json:"author,omitempty"`
+	Text          string   `json:"text"`
+	Views         string   `json:"views,omitempty"`
+	ForwardedFrom string   `json:"forwarded_from,omitempty"`
+	ReplyToID     string   `json:"reply_to_id,omitempty"`
+	Links         []string `json:"links,omitempty"`
+	Photos        []string `json:"photos,omitempty"`
+	Videos        []string `json:"videos,omitempty"`
+	Documents     []string `json:"documents,omitempty"`
+	DocumentURLs  []string `json:"document_urls,omitempty"`
+	PollOptions   []string `json:"poll_options,omitempty"`
 }
 
 // Config holds the application configuration.
 type Config struct {
-	ChannelName    string
-	ScrollDuration time.Duration
-	OutputDir      string
+	Channels         []string
+	ScrollDuration   time.Duration
+	OutputDir        string
+	Concurrency      int
+	StopAfter        time.Duration
+	CancelAfter      time.Duration
+	Sink             string
+	WebhookURL       string
+	Format           string
+	Since            string
+	Full             bool
+	MetricsAddr      string
+	MediaStore       string
+	S3Endpoint       string
+	S3Bucket         string
+	MediaConcurrency int
+
+	MastodonServerAddress string
+	MastodonAccessToken   string
+	MastodonVisibility    string
+	MastodonMaxCharacters int
+	MastodonFooter        string
 }
 
-// NewConfig creates a new Config instance from environment variables and user input.
+// NewConfig creates a new Config instance from CLI flags, environment
+// variables, and (as a last resort, for a single channel) interactive input.
 func NewConfig() (*Config, error) {
 	if err := godotenv.Load(); err != nil {
 		return nil, fmt.Errorf("error loading .env file: %w", err)
 	}
 
-	channelName := getEnvOrPrompt("CHANNEL_NAME", "Enter the channel name: ", extractChannelName)
-	scrollDuration, err := readScrollDuration()
+	channelsFile := flag.String("channels-file", "", "path to a file with one channel name/URL per line")
+	concurrency := flag.Int("concurrency", getEnvIntOrDefault("CONCURRENCY", 1), "number of channels to scrape concurrently")
+	stopAfter := flag.Duration("stop-after", 0, "stop dispatching new channels after this long, letting in-flight scrapes finish (0 = no limit)")
+	cancelAfter := flag.Duration("cancel-after", 0, "hard-cancel all scraping after this long, aborting in-flight work (0 = no limit)")
+	sink := flag.String("sink", getEnvOrDefault("SINK", "file"), "output sink: file, stdout, or webhook")
+	webhookURL := flag.String("webhook-url", getEnvOrDefault("WEBHOOK_URL", ""), "destination URL when --sink=webhook")
+	format := flag.String("format", getEnvOrDefault("FORMAT", "json"), "output format: json (array), ndjson, or csv")
+	since := flag.String("since", "", "only write posts newer than this message ID or RFC3339 timestamp (posts already in the persisted state are still skipped)")
+	full := flag.Bool("full", false, "ignore persisted state and rescrape the channel from scratch")
+	metricsAddr := flag.String("metrics-addr", getEnvOrDefault("METRICS_ADDR", ""), "if set, serve Prometheus-style counters at http://<addr>/metrics")
+	flag.Parse()
+
+	channels, err := resolveChannels(flag.Args(), *channelsFile)
 	if err != nil {
-		return nil, fmt.Errorf("error reading scroll duration: %w", err)
+		return nil, err
 	}
 
+	scrollDuration := readScrollDuration()
+
 	outputDir := getEnvOrDefault("OUTPUT_DIR", "posts")
 
 	return &Config{
-		ChannelName:    channelName,
-		ScrollDuration: scrollDuration,
-		OutputDir:      outputDir,
+		Channels:         channels,
+		ScrollDuration:   scrollDuration,
+		OutputDir:        outputDir,
+		Concurrency:      *concurrency,
+		StopAfter:        *stopAfter,
+		CancelAfter:      *cancelAfter,
+		Sink:             *sink,
+		WebhookURL:       *webhookURL,
+		Format:           *format,
+		Since:            *since,
+		Full:             *full,
+		MetricsAddr:      *metricsAddr,
+		MediaStore:       getEnvOrDefault("MEDIA_STORE", "local"),
+		S3Endpoint:       getEnvOrDefault("S3_ENDPOINT", ""),
+		S3Bucket:         getEnvOrDefault("S3_BUCKET", ""),
+		MediaConcurrency: getEnvIntOrDefault("MEDIA_CONCURRENCY", 4),
+
+		MastodonServerAddress: os.Getenv("MASTODON_SERVER_ADDRESS"),
+		MastodonAccessToken:   os.Getenv("MASTODON_ACCESS_TOKEN"),
+		MastodonVisibility:    getEnvOrDefault("MASTODON_TOOT_VISIBILITY", "public"),
+		MastodonMaxCharacters: getEnvIntOrDefault("MASTODON_TOOT_MAX_CHARACTERS", 500),
+		MastodonFooter:        os.Getenv("MASTODON_TOOT_FOOTER"),
 	}, nil
 }
 
+// resolveChannels determines the list of channels to scrape, preferring (in
+// order) CLI positional arguments, --channels-file, the CHANNELS env var
+// (comma-separated), and finally an interactive prompt for a single channel.
+func resolveChannels(args []string, channelsFile string) ([]string, error) {
+	var channels []string
+
+	for _, arg := range args {
+		channels = append(channels, extractChannelName(arg))
+	}
+
+	if len(channels) == 0 && channelsFile != "" {
+		lines, err := readLines(channelsFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading channels file: %w", err)
+		}
+		for _, line := range lines {
+			if line == "" {
+				continue
+			}
+			channels = append(channels, extractChannelName(line))
+		}
+	}
+
+	if len(channels) == 0 {
+		if raw := os.Getenv("CHANNELS"); raw != "" {
+			for _, part := range strings.Split(raw, ",") {
+				part = strings.TrimSpace(part)
+				if part == "" {
+					continue
+				}
+				channels = append(channels, extractChannelName(part))
+			}
+		}
+	}
+
+	if len(channels) == 0 {
+		channels = append(channels, getEnvOrPrompt("CHANNEL_NAME", "Enter the channel name: ", extractChannelName))
+	}
+
+	return channels, nil
+}
+
+// readLines reads a text file and returns its lines, with leading and
+// trailing whitespace of the whole file trimmed; empty lines in between are
+// returned as empty strings.
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n"), nil
+}
+
 // getEnvOrPrompt retrieves an environment variable or prompts the user if not found.
 func getEnvOrPrompt(envVar, prompt string, transformFunc func(string) string) string {
 	value := os.Getenv(envVar)
@@ -66,18 +183,35 @@ func getEnvOrDefault(envVar, defaultValue string) string {
 	return value
 }
 
-// readScrollDuration prompts the user for the scrolling duration.
-func readScrollDuration() (time.Duration, error) {
-	var minutes int
-	for {
-		fmt.Print("How many minutes do you want to scroll? (1-60): ")
-		_, err := fmt.Scanf("%d", &minutes)
-		if err == nil && minutes >= 1 && minutes <= 60 {
-			break
+// getEnvIntOrDefault retrieves an environment variable as an int or returns a default value.
+func getEnvIntOrDefault(envVar string, defaultValue int) int {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return defaultValue
+	}
+	var parsed int
+	if _, err := fmt.Sscanf(value, "%d", &parsed); err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// defaultScrollMinutes is used when SCROLL_MINUTES is unset or invalid, so an
+// unattended cron invocation gets a sane upper bound on scroll time instead
+// of blocking on an interactive prompt. In practice a channel's scrape
+// usually ends well before this via maxConsecutiveSeen/maxSequentialTimeouts.
+const defaultScrollMinutes = 15
+
+// readScrollDuration returns the scroll duration from SCROLL_MINUTES
+// (1-60), or defaultScrollMinutes if it is unset or out of range.
+func readScrollDuration() time.Duration {
+	if raw := os.Getenv("SCROLL_MINUTES"); raw != "" {
+		var minutes int
+		if _, err := fmt.Sscanf(raw, "%d", &minutes); err == nil && minutes >= 1 && minutes <= 60 {
+			return time.Duration(minutes) * time.Minute
 		}
-		fmt.Println("Invalid input. Please enter a number between 1 and 60.")
 	}
-	return time.Duration(minutes) * time.Minute, nil
+	return defaultScrollMinutes * time.Minute
 }
 
 // extractChannelName extracts the channel name from the given string.
@@ -91,10 +225,11 @@ func extractChannelName(channelString string) string {
 	return parts[0]
 }
 
-// createUniqueFilename generates a unique filename for the collected posts.
-func createUniqueFilename(channelName string) string {
+// createUniqueFilename generates a unique filename for the collected posts,
+// using ext (without a leading dot) as the file extension.
+func createUniqueFilename(channelName, ext string) string {
 	timestamp := time.Now().Format("20060102150405")
-	return fmt.Sprintf("%s_posts_%s.json", channelName, timestamp)
+	return fmt.Sprintf("%s_posts_%s.%s", channelName, timestamp, ext)
 }
 
 // createFolderIfNotExists creates the output directory if it doesn't exist.
@@ -110,116 +245,114 @@ func createFolderIfNotExists(folderName string) error {
 	return nil
 }
 
-// scrapeChannel scrapes posts from the specified Telegram channel.
-func scrapeChannel(ctx context.Context, url string, cfg *Config) error {
-	log.Println("Navigating to the channel...")
-	if err := chromedp.Run(ctx,
-		chromedp.Navigate(url),
-		chromedp.WaitVisible(".tgme_widget_message_text", chromedp.ByQuery),
-	); err != nil {
-		return fmt.Errorf("error navigating to channel: %w", err)
-	}
-	log.Println("Successfully navigated to the channel.")
-
-	postSet := make(map[string]bool)
-	filename := createUniqueFilename(cfg.ChannelName)
-	filePath := filepath.Join(cfg.OutputDir, filename)
-
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("error opening/creating JSON file: %w", err)
-	}
-	defer file.Close()
-
-	log.Println("Starting to scroll and collect unique posts...")
-	endTime := time.Now().Add(cfg.ScrollDuration)
-	for time.Now().Before(endTime) {
-		if err := collectPosts(ctx, postSet, file); err != nil {
-			log.Println(err)
+// newMediaDownloader builds the MediaDownloader for cfg.MediaStore.
+func newMediaDownloader(cfg *Config) (*MediaDownloader, error) {
+	var store MediaStore
+	switch cfg.MediaStore {
+	case "local", "":
+		store = NewLocalMediaStore(cfg.OutputDir)
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required when MEDIA_STORE=s3")
 		}
-
-		remainingTime := time.Until(endTime)
-		log.Printf("Collected %d unique posts so far. Time left: %02d:%02d",
-			len(postSet), int(remainingTime.Minutes()), int(remainingTime.Seconds())%60)
+		store = NewS3MediaStore(cfg.S3Endpoint, cfg.S3Bucket)
+	default:
+		return nil, fmt.Errorf("unknown MEDIA_STORE %q", cfg.MediaStore)
 	}
-
-	log.Println("Finished scrolling and collecting unique posts.")
-	return nil
+	return NewMediaDownloader(store, cfg.MediaConcurrency), nil
 }
 
-// collectPosts handles the scraping and storing of posts.
-func collectPosts(ctx context.Context, postSet map[string]bool, file *os.File) error {
-	var contentList []string
-	if err := chromedp.Run(ctx,
-		chromedp.ScrollIntoView(".tgme_widget_message_text"),
-		chromedp.Sleep(4*time.Second),
-		chromedp.Evaluate(`Array.from(document.querySelectorAll('.tgme_widget_message_text')).map(el => el.innerText)`, &contentList),
-	); err != nil {
-		return fmt.Errorf("error scraping posts: %w", err)
-	}
-
-	for _, content := range contentList {
-		if err := processPost(content, postSet, file); err != nil {
-			log.Println(err)
-		}
+// newPublisher builds the Mastodon Publisher, or nil if no access token is
+// configured.
+func newPublisher(cfg *Config) Publisher {
+	if cfg.MastodonAccessToken == "" {
+		return nil
 	}
-	return nil
+	return NewMastodonPublisher(cfg)
 }
 
-// processPost processes and saves a single post if it is unique.
-func processPost(content string, postSet map[string]bool, file *os.File) error {
-	if !postSet[content] {
-		postSet[content] = true
-		post := Post{Text: content}
-		if err := writePostToFile(post, file); err != nil {
-			return err
+// newSink builds the PostSink selected by cfg.Sink.
+func newSink(cfg *Config) (PostSink, error) {
+	switch cfg.Sink {
+	case "file", "":
+		return NewFileSink(cfg.OutputDir, cfg.Format)
+	case "stdout":
+		return NewStdoutSink(), nil
+	case "webhook":
+		if cfg.WebhookURL == "" {
+			return nil, fmt.Errorf("--webhook-url is required when --sink=webhook")
 		}
-		log.Println("Collected unique post:", content)
+		return NewWebhookSink(cfg.WebhookURL), nil
+	default:
+		return nil, fmt.Errorf("unknown sink %q", cfg.Sink)
 	}
-	return nil
 }
 
-// writePostToFile writes a post to the specified file.
-func writePostToFile(post Post, file *os.File) error {
-	postData, err := json.Marshal(post)
+func main() {
+	cfg, err := NewConfig()
 	if err != nil {
-		return fmt.Errorf("error marshalling post: %w", err)
+		log.Fatalf("error creating configuration: %v", err)
 	}
 
-	if _, err := file.Write(postData); err != nil {
-		return fmt.Errorf("error writing post to file: %w", err)
+	if err := createFolderIfNotExists(cfg.OutputDir); err != nil {
+		log.Fatalf("error creating output directory: %v", err)
 	}
-	if _, err := file.WriteString("\n"); err != nil {
-		return fmt.Errorf("error writing newline to file: %w", err)
+
+	sink, err := newSink(cfg)
+	if err != nil {
+		log.Fatalf("error creating sink: %v", err)
 	}
-	return nil
-}
+	defer sink.Close()
+
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if cfg.CancelAfter > 0 {
+		ctx, cancel = context.WithTimeout(ctx, cfg.CancelAfter)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
 
-func main() {
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("headless", true),
 		chromedp.Flag("disable-gpu", false),
 		chromedp.Flag("start-maximized", true),
 	)
 
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	defer cancel()
+	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, opts...)
+	defer allocCancel()
 
-	ctx, cancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
-	defer cancel()
+	metrics := NewMetrics()
+	if cfg.MetricsAddr != "" {
+		StartMetricsServer(cfg.MetricsAddr, metrics)
+	}
 
-	cfg, err := NewConfig()
+	mediaDownloader, err := newMediaDownloader(cfg)
 	if err != nil {
-		log.Fatalf("error creating configuration: %v", err)
+		log.Fatalf("error creating media downloader: %v", err)
 	}
 
-	url := "https://t.me/s/" + cfg.ChannelName
+	publisher := newPublisher(cfg)
 
-	if err := createFolderIfNotExists(cfg.OutputDir); err != nil {
-		log.Fatalf("error creating output directory: %v", err)
+	events := make(chan ScrapeEvent)
+	go logScrapeEvents(events)
+
+	pool := NewWorkerPool(allocCtx, cfg, sink, metrics, mediaDownloader, publisher, events)
+	pool.Run(cfg.Channels)
+	close(events)
+
+	if err := writeChannelsOPML(cfg.OutputDir, cfg.Channels); err != nil {
+		log.Printf("error writing channels OPML: %v", err)
 	}
+}
 
-	if err := scrapeChannel(ctx, url, cfg); err != nil {
-		log.Fatalf("error scraping channel: %v", err)
+// logScrapeEvents prints each ScrapeEvent as it arrives until the channel is closed.
+func logScrapeEvents(events <-chan ScrapeEvent) {
+	for ev := range events {
+		if ev.Err != nil {
+			log.Printf("[%s] %s: %v", ev.Channel, ev.Status, ev.Err)
+			continue
+		}
+		log.Printf("[%s] %s", ev.Channel, ev.Status)
 	}
 }
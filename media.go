@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MediaStore persists downloaded media bytes and returns the reference
+// (local path or URL) that should replace the original Telegram CDN link in
+// the stored Post.
+type MediaStore interface {
+	Store(channel, messageID, filename string, content []byte) (string, error)
+}
+
+// fileLocks serializes writes to the same destination path, mirroring the
+// per-file locking go4.org/lock provides, so two workers downloading
+// identical content never clobber each other's write.
+type fileLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newFileLocks() *fileLocks {
+	return &fileLocks{locks: make(map[string]*sync.Mutex)}
+}
+
+// Lock acquires the lock for key and returns a function that releases it.
+func (f *fileLocks) Lock(key string) func() {
+	f.mu.Lock()
+	l, ok := f.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		f.locks[key] = l
+	}
+	f.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// LocalMediaStore writes media under baseDir/media/<channel>/<messageID>/,
+// naming each file by the content's SHA-256 hash so identical uploads across
+// posts are deduplicated on disk.
+type LocalMediaStore struct {
+	baseDir string
+	locks   *fileLocks
+}
+
+// NewLocalMediaStore creates a LocalMediaStore rooted at baseDir.
+func NewLocalMediaStore(baseDir string) *LocalMediaStore {
+	return &LocalMediaStore{baseDir: baseDir, locks: newFileLocks()}
+}
+
+func (s *LocalMediaStore) Store(channel, messageID, filename string, content []byte) (string, error) {
+	dir := filepath.Join(s.baseDir, "media", channel, messageID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating media directory: %w", err)
+	}
+
+	destPath := filepath.Join(dir, contentHashFilename(filename, content))
+	unlock := s.locks.Lock(destPath)
+	defer unlock()
+
+	if _, err := os.Stat(destPath); err == nil {
+		return destPath, nil
+	}
+	if err := os.WriteFile(destPath, content, 0644); err != nil {
+		return "", fmt.Errorf("error writing media file: %w", err)
+	}
+	return destPath, nil
+}
+
+// S3MediaStore PUTs media to an S3-compatible (e.g. SeaweedFS) endpoint,
+// keyed the same way LocalMediaStore lays out files on disk.
+type S3MediaStore struct {
+	endpoint string
+	bucket   string
+	client   *http.Client
+	locks    *fileLocks
+}
+
+// NewS3MediaStore creates an S3MediaStore targeting endpoint/bucket.
+func NewS3MediaStore(endpoint, bucket string) *S3MediaStore {
+	return &S3MediaStore{
+		endpoint: endpoint,
+		bucket:   bucket,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		locks:    newFileLocks(),
+	}
+}
+
+func (s *S3MediaStore) Store(channel, messageID, filename string, content []byte) (string, error) {
+	key := path.Join(channel, messageID, contentHashFilename(filename, content))
+	url := fmt.Sprintf("%s/%s/%s", strings.TrimRight(s.endpoint, "/"), s.bucket, key)
+
+	unlock := s.locks.Lock(url)
+	defer unlock()
+
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("error building S3 upload request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error uploading media to S3: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("S3 upload returned status %d", resp.StatusCode)
+	}
+	return url, nil
+}
+
+// contentHashFilename names a stored file by the SHA-256 hash of its
+// content, preserving filename's extension.
+func contentHashFilename(filename string, content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]) + filepath.Ext(filename)
+}
+
+// MediaDownloader fetches the photo/video/document URLs referenced by a post
+// and rewrites them in place to point at the stored copy, running downloads
+// on a bounded goroutine pool.
+type MediaDownloader struct {
+	store       MediaStore
+	client      *http.Client
+	concurrency int
+}
+
+// NewMediaDownloader creates a MediaDownloader backed by store, downloading
+// at most concurrency files at a time.
+func NewMediaDownloader(store MediaStore, concurrency int) *MediaDownloader {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &MediaDownloader{
+		store:       store,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		concurrency: concurrency,
+	}
+}
+
+// DownloadPost fetches every media URL referenced by post and replaces it
+// with the stored reference, skipping (and logging) any that fail.
+func (d *MediaDownloader) DownloadPost(channel string, post *Post) {
+	type target struct {
+		urls *[]string
+		idx  int
+	}
+
+	var targets []target
+	for _, urls := range []*[]string{&post.Photos, &post.Videos, &post.DocumentURLs} {
+		for i := range *urls {
+			targets = append(targets, target{urls: urls, idx: i})
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	sem := make(chan struct{}, d.concurrency)
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ref, err := d.fetchAndStore(channel, post.MessageID, (*t.urls)[t.idx])
+			if err != nil {
+				log.Printf("[%s] error downloading media %s: %v", channel, (*t.urls)[t.idx], err)
+				return
+			}
+			(*t.urls)[t.idx] = ref
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (d *MediaDownloader) fetchAndStore(channel, messageID, url string) (string, error) {
+	resp, err := d.client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("error fetching media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("media fetch returned status %d", resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading media body: %w", err)
+	}
+
+	return d.store.Store(channel, messageID, path.Base(url), content)
+}
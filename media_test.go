@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestContentHashFilename(t *testing.T) {
+	a := contentHashFilename("photo.jpg", []byte("content"))
+	b := contentHashFilename("other-name.jpg", []byte("content"))
+	if a != b {
+		t.Errorf("expected identical content to hash to the same filename regardless of source name, got %q and %q", a, b)
+	}
+
+	c := contentHashFilename("photo.jpg", []byte("different content"))
+	if a == c {
+		t.Error("expected different content to hash to different filenames")
+	}
+
+	if got, want := a[len(a)-4:], ".jpg"; got != want {
+		t.Errorf("extension = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+)
+
+// Metrics holds Prometheus-style counters for the scrape run, optionally
+// exposed over HTTP via --metrics-addr.
+type Metrics struct {
+	postsCollected uint64
+	scrollTimeouts uint64
+	retries        uint64
+}
+
+// NewMetrics creates an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// IncPostsCollected adds n to the posts_collected counter.
+func (m *Metrics) IncPostsCollected(n int) {
+	atomic.AddUint64(&m.postsCollected, uint64(n))
+}
+
+// IncScrollTimeouts increments the scroll_timeouts counter.
+func (m *Metrics) IncScrollTimeouts() {
+	atomic.AddUint64(&m.scrollTimeouts, 1)
+}
+
+// IncRetries increments the retries counter.
+func (m *Metrics) IncRetries() {
+	atomic.AddUint64(&m.retries, 1)
+}
+
+// ServeHTTP renders the counters in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "# TYPE scraper_posts_collected counter\n")
+	fmt.Fprintf(w, "scraper_posts_collected %d\n", atomic.LoadUint64(&m.postsCollected))
+	fmt.Fprintf(w, "# TYPE scraper_scroll_timeouts counter\n")
+	fmt.Fprintf(w, "scraper_scroll_timeouts %d\n", atomic.LoadUint64(&m.scrollTimeouts))
+	fmt.Fprintf(w, "# TYPE scraper_retries counter\n")
+	fmt.Fprintf(w, "scraper_retries %d\n", atomic.LoadUint64(&m.retries))
+}
+
+// StartMetricsServer starts an HTTP server exposing m at /metrics on addr. It
+// runs in the background and logs an error if the listener fails.
+func StartMetricsServer(addr string, m *Metrics) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("metrics server stopped: %v", err)
+		}
+	}()
+}
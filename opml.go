@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// opmlDocument is the minimal OPML 2.0 shape needed to list scraped channels
+// as feed-reader-compatible outlines.
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title string `xml:"title"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Text    string `xml:"text,attr"`
+	Title   string `xml:"title,attr"`
+	Type    string `xml:"type,attr"`
+	XMLURL  string `xml:"xmlUrl,attr"`
+	HTMLURL string `xml:"htmlUrl,attr"`
+}
+
+// writeChannelsOPML writes an OPML outline listing channels, one per
+// outline, to OutputDir/channels.opml so the scraped channel list can be
+// imported into any feed reader.
+func writeChannelsOPML(outputDir string, channels []string) error {
+	doc := opmlDocument{
+		Version: "2.0",
+		Head:    opmlHead{Title: "Scraped Telegram Channels"},
+	}
+	for _, channel := range channels {
+		url := "https://t.me/s/" + channel
+		doc.Body.Outlines = append(doc.Body.Outlines, opmlOutline{
+			Text:    channel,
+			Title:   channel,
+			Type:    "rss",
+			XMLURL:  url,
+			HTMLURL: url,
+		})
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling channels OPML: %w", err)
+	}
+
+	path := filepath.Join(outputDir, "channels.opml")
+	if err := os.WriteFile(path, append([]byte(xml.Header), data...), 0644); err != nil {
+		return fmt.Errorf("error writing channels OPML: %w", err)
+	}
+	return nil
+}
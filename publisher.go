@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattn/go-mastodon"
+)
+
+// Publisher forwards a collected post to an external syndication target.
+type Publisher interface {
+	Publish(channel string, post Post) error
+}
+
+// tootMapPath returns the path of the message-ID -> toot-ID mapping used to
+// make republishing idempotent across runs.
+func tootMapPath(outputDir, channel string) string {
+	return filepath.Join(outputDir, ".state", channel+"_toots.json")
+}
+
+// loadTootMap loads the persisted message-ID -> toot-ID mapping for channel,
+// returning an empty map if none has been saved yet.
+func loadTootMap(outputDir, channel string) (map[string]string, error) {
+	data, err := os.ReadFile(tootMapPath(outputDir, channel))
+	if os.IsNotExist(err) {
+		return make(map[string]string), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading toot map for %s: %w", channel, err)
+	}
+
+	m := make(map[string]string)
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("error parsing toot map for %s: %w", channel, err)
+	}
+	return m, nil
+}
+
+// saveTootMap persists the message-ID -> toot-ID mapping for channel.
+func saveTootMap(outputDir, channel string, m map[string]string) error {
+	dir := filepath.Join(outputDir, ".state")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating state directory: %w", err)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("error marshalling toot map for %s: %w", channel, err)
+	}
+	if err := os.WriteFile(tootMapPath(outputDir, channel), data, 0644); err != nil {
+		return fmt.Errorf("error writing toot map for %s: %w", channel, err)
+	}
+	return nil
+}
+
+// MastodonPublisher toots collected posts to a Mastodon instance, splitting
+// long posts into a numbered thread and attaching any downloaded media.
+type MastodonPublisher struct {
+	client        *mastodon.Client
+	visibility    string
+	maxCharacters int
+	footer        string
+	outputDir     string
+	httpClient    *http.Client
+
+	mu      sync.Mutex
+	tootMap map[string]map[string]string // channel -> message ID -> toot ID
+}
+
+// NewMastodonPublisher creates a MastodonPublisher from the MASTODON_*
+// environment variables.
+func NewMastodonPublisher(cfg *Config) *MastodonPublisher {
+	client := mastodon.NewClient(&mastodon.Config{
+		Server:      cfg.MastodonServerAddress,
+		AccessToken: cfg.MastodonAccessToken,
+	})
+	return &MastodonPublisher{
+		client:        client,
+		visibility:    cfg.MastodonVisibility,
+		maxCharacters: cfg.MastodonMaxCharacters,
+		footer:        cfg.MastodonFooter,
+		outputDir:     cfg.OutputDir,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		tootMap:       make(map[string]map[string]string),
+	}
+}
+
+// Publish toots post, skipping it if it was already posted in a prior run.
+func (p *MastodonPublisher) Publish(channel string, post Post) error {
+	p.mu.Lock()
+	channelMap, ok := p.tootMap[channel]
+	if !ok {
+		loaded, err := loadTootMap(p.outputDir, channel)
+		if err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		channelMap = loaded
+		p.tootMap[channel] = channelMap
+	}
+	if _, already := channelMap[post.MessageID]; already {
+		p.mu.Unlock()
+		return nil
+	}
+	p.mu.Unlock()
+
+	ctx := context.Background()
+	mediaIDs, err := p.uploadMedia(ctx, post)
+	if err != nil {
+		return fmt.Errorf("error uploading media to Mastodon: %w", err)
+	}
+
+	parts := splitIntoToots(post.Text, p.maxCharacters, p.footer)
+
+	var firstID mastodon.ID
+	var inReplyTo mastodon.ID
+	for i, part := range parts {
+		toot := &mastodon.Toot{
+			Status:      part,
+			Visibility:  p.visibility,
+			InReplyToID: inReplyTo,
+		}
+		if i == 0 {
+			toot.MediaIDs = mediaIDs
+		}
+		status, err := p.client.PostStatus(ctx, toot)
+		if err != nil {
+			return fmt.Errorf("error posting toot %d/%d: %w", i+1, len(parts), err)
+		}
+		inReplyTo = status.ID
+		if i == 0 {
+			firstID = status.ID
+		}
+	}
+
+	p.mu.Lock()
+	p.tootMap[channel][post.MessageID] = string(firstID)
+	err = saveTootMap(p.outputDir, channel, p.tootMap[channel])
+	p.mu.Unlock()
+	return err
+}
+
+// uploadMedia uploads the post's photo/video/document attachments and
+// returns their Mastodon media IDs.
+func (p *MastodonPublisher) uploadMedia(ctx context.Context, post Post) ([]mastodon.ID, error) {
+	var ids []mastodon.ID
+	for _, urls := range [][]string{post.Photos, post.Videos, post.DocumentURLs} {
+		for _, url := range urls {
+			content, err := p.fetch(url)
+			if err != nil {
+				return nil, err
+			}
+			attachment, err := p.client.UploadMediaFromReader(ctx, bytes.NewReader(content))
+			if err != nil {
+				return nil, fmt.Errorf("error uploading %s: %w", url, err)
+			}
+			ids = append(ids, attachment.ID)
+		}
+	}
+	return ids, nil
+}
+
+// fetch reads media referenced by url, which may be a local filesystem path
+// (as produced by LocalMediaStore) or an http(s) URL (S3MediaStore, or an
+// un-downloaded Telegram CDN link).
+func (p *MastodonPublisher) fetch(url string) ([]byte, error) {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		resp, err := p.httpClient.Get(url)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching %s: %w", url, err)
+		}
+		defer resp.Body.Close()
+		return io.ReadAll(resp.Body)
+	}
+	return os.ReadFile(url)
+}
+
+// splitIntoToots breaks text into chunks that fit within maxCharacters,
+// appending footer to every chunk and a "(i/N)" thread marker when the post
+// had to be split into more than one toot.
+func splitIntoToots(text string, maxCharacters int, footer string) []string {
+	if maxCharacters <= 0 {
+		maxCharacters = 500
+	}
+	footerPart := ""
+	if footer != "" {
+		footerPart = "\n\n" + footer
+	}
+	const markerReserve = 12 // room for " (99/99)" in the worst realistic case
+	budget := maxCharacters - utf8.RuneCountInString(footerPart) - markerReserve
+	if budget < 1 {
+		budget = 1
+	}
+
+	var chunks []string
+	remaining := text
+	for utf8.RuneCountInString(remaining) > budget {
+		cutByte := runeByteIndex(remaining, budget)
+		cut := cutByte
+		if idx := strings.LastIndexAny(remaining[:cutByte], " \n"); idx > 0 {
+			cut = idx
+		}
+		chunks = append(chunks, strings.TrimSpace(remaining[:cut]))
+		remaining = strings.TrimSpace(remaining[cut:])
+	}
+	chunks = append(chunks, remaining)
+
+	total := len(chunks)
+	for i, chunk := range chunks {
+		if total > 1 {
+			chunk = fmt.Sprintf("%s (%d/%d)", chunk, i+1, total)
+		}
+		chunks[i] = chunk + footerPart
+	}
+	return chunks
+}
+
+// runeByteIndex returns the byte offset of the nth rune in s, or len(s) if s
+// has fewer than n runes, so slicing at the result never splits a multi-byte
+// rune (unlike a plain byte-length budget).
+func runeByteIndex(s string, n int) int {
+	count := 0
+	for i := range s {
+		if count == n {
+			return i
+		}
+		count++
+	}
+	return len(s)
+}
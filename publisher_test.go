@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitIntoTootsFitsWithinBudget(t *testing.T) {
+	text := strings.Repeat("hello world ", 100)
+	parts := splitIntoToots(text, 100, "")
+	if len(parts) < 2 {
+		t.Fatalf("expected text to be split into multiple toots, got %d", len(parts))
+	}
+	for i, part := range parts {
+		if n := utf8.RuneCountInString(part); n > 100 {
+			t.Errorf("part %d has %d runes, want <= 100: %q", i, n, part)
+		}
+	}
+}
+
+func TestSplitIntoTootsNonASCII(t *testing.T) {
+	// A run of Cyrillic text is 2 bytes per rune; a byte-length budget would
+	// cut this at ~half the requested character count and could slice a
+	// multi-byte rune in half, producing invalid UTF-8.
+	text := strings.Repeat("привет мир ", 60)
+	parts := splitIntoToots(text, 100, "")
+	if len(parts) < 2 {
+		t.Fatalf("expected text to be split into multiple toots, got %d", len(parts))
+	}
+	for i, part := range parts {
+		if !utf8.ValidString(part) {
+			t.Errorf("part %d is not valid UTF-8: %q", i, part)
+		}
+		if n := utf8.RuneCountInString(part); n > 100 {
+			t.Errorf("part %d has %d runes, want <= 100: %q", i, n, part)
+		}
+	}
+}
+
+func TestSplitIntoTootsSingleChunkHasNoMarker(t *testing.T) {
+	parts := splitIntoToots("short toot", 500, "")
+	if len(parts) != 1 {
+		t.Fatalf("expected a single chunk, got %d", len(parts))
+	}
+	if strings.Contains(parts[0], "(1/1)") {
+		t.Errorf("expected no thread marker on a single-chunk toot, got %q", parts[0])
+	}
+}
+
+func TestSplitIntoTootsAppendsFooter(t *testing.T) {
+	parts := splitIntoToots("short toot", 500, "-- via scraper")
+	if !strings.HasSuffix(parts[0], "-- via scraper") {
+		t.Errorf("expected footer to be appended, got %q", parts[0])
+	}
+}
+
+func TestRuneByteIndex(t *testing.T) {
+	s := "привет"
+	idx := runeByteIndex(s, 2)
+	if s[:idx] != "пр" {
+		t.Errorf("runeByteIndex(%q, 2) sliced to %q, want \"пр\"", s, s[:idx])
+	}
+
+	if got := runeByteIndex(s, 100); got != len(s) {
+		t.Errorf("runeByteIndex with n beyond the string length = %d, want %d", got, len(s))
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ScrapeEvent reports the progress of a single channel's scrape.
+type ScrapeEvent struct {
+	Channel string
+	Status  string
+	Err     error
+}
+
+// WorkerPool scrapes a set of channels concurrently, bounded by
+// cfg.Concurrency. Each worker owns its own chromedp tab context derived from
+// the shared ExecAllocator passed in as allocCtx.
+type WorkerPool struct {
+	allocCtx  context.Context
+	cfg       *Config
+	sink      PostSink
+	metrics   *Metrics
+	media     *MediaDownloader
+	publisher Publisher
+	events    chan<- ScrapeEvent
+}
+
+// NewWorkerPool creates a WorkerPool. allocCtx must be a context returned by
+// chromedp.NewExecAllocator.
+func NewWorkerPool(allocCtx context.Context, cfg *Config, sink PostSink, metrics *Metrics, media *MediaDownloader, publisher Publisher, events chan<- ScrapeEvent) *WorkerPool {
+	return &WorkerPool{
+		allocCtx:  allocCtx,
+		cfg:       cfg,
+		sink:      sink,
+		metrics:   metrics,
+		media:     media,
+		publisher: publisher,
+		events:    events,
+	}
+}
+
+// Run scrapes every channel in channels, using up to cfg.Concurrency workers.
+// It stops dispatching new channels once cfg.StopAfter elapses or allocCtx is
+// cancelled, and blocks until every dispatched scrape has finished.
+func (p *WorkerPool) Run(channels []string) {
+	concurrency := p.cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	jobs := make(chan string)
+	var stopTimer *time.Timer
+	if p.cfg.StopAfter > 0 {
+		stopTimer = time.NewTimer(p.cfg.StopAfter)
+		defer stopTimer.Stop()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.worker(jobs)
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		var stopC <-chan time.Time
+		if stopTimer != nil {
+			stopC = stopTimer.C
+		}
+		for _, channel := range channels {
+			select {
+			case <-stopC:
+				return
+			default:
+			}
+			select {
+			case jobs <- channel:
+			case <-stopC:
+				return
+			case <-p.allocCtx.Done():
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+}
+
+// worker pulls channel names off jobs and scrapes each one until jobs is
+// closed or the allocator context is cancelled.
+func (p *WorkerPool) worker(jobs <-chan string) {
+	for channel := range jobs {
+		p.events <- ScrapeEvent{Channel: channel, Status: "started"}
+
+		url := "https://t.me/s/" + channel
+		if err := scrapeChannel(p.allocCtx, url, channel, p.cfg, p.sink, p.metrics, p.media, p.publisher); err != nil {
+			p.events <- ScrapeEvent{Channel: channel, Status: "failed", Err: err}
+		} else {
+			p.events <- ScrapeEvent{Channel: channel, Status: "finished"}
+		}
+	}
+}
+
+// scrapeChannel scrapes posts from the specified Telegram channel into sink,
+// resuming from the channel's persisted state unless cfg.Full is set. It
+// drives the scroll with waitForNewPosts, backing off when nothing new
+// loads and recreating the tab if navigation fails outright.
+func scrapeChannel(allocCtx context.Context, url, channel string, cfg *Config, sink PostSink, metrics *Metrics, media *MediaDownloader, publisher Publisher) (err error) {
+	ctx, cancel, err := navigateNewTab(allocCtx, url, channel)
+	if err != nil {
+		return err
+	}
+	// cancel is reassigned whenever the tab is recreated below; wrapping it
+	// in a closure (rather than `defer cancel()`) means this always cancels
+	// whichever tab is current when scrapeChannel returns, not the first one.
+	// navigateNewTab returns a nil cancel alongside a non-nil error, so guard
+	// against that case too (e.g. a retry navigation that itself fails).
+	defer func() {
+		if cancel != nil {
+			cancel()
+		}
+	}()
+
+	state := newChannelState()
+	if !cfg.Full {
+		loaded, loadErr := loadChannelState(cfg.OutputDir, channel)
+		if loadErr != nil {
+			return loadErr
+		}
+		state = loaded
+	}
+	// Always persist whatever progress was made, even on an early return, so
+	// a failed run doesn't force the next one to rescrape from scratch.
+	defer func() {
+		if saveErr := state.Save(cfg.OutputDir, channel); saveErr != nil {
+			if err == nil {
+				err = saveErr
+			} else {
+				log.Printf("[%s] error saving state: %v", channel, saveErr)
+			}
+		}
+	}()
+
+	cutoff, err := parseSinceCutoff(cfg.Since)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[%s] Starting to scroll and collect unique posts...", channel)
+	endTime := time.Now().Add(cfg.ScrollDuration)
+	consecutiveSeen := 0
+	sequentialTimeouts := 0
+	backoff := scrollBaseBackoff
+
+	for time.Now().Before(endTime) && consecutiveSeen < maxConsecutiveSeen && sequentialTimeouts < maxSequentialTimeouts {
+		newNodes, err := waitForNewPosts(ctx)
+		if err != nil {
+			metrics.IncRetries()
+			log.Printf("[%s] scroll failed, recreating tab: %v", channel, err)
+			cancel()
+			ctx, cancel, err = navigateNewTab(allocCtx, resumeURL(url, state.LastMessageID), channel)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !newNodes {
+			sequentialTimeouts++
+			metrics.IncScrollTimeouts()
+			log.Printf("[%s] no new posts (timeout %d/%d), backing off %s",
+				channel, sequentialTimeouts, maxSequentialTimeouts, backoff)
+			time.Sleep(backoff)
+			if backoff < scrollMaxBackoff {
+				backoff *= 2
+			}
+			continue
+		}
+		sequentialTimeouts = 0
+		backoff = scrollBaseBackoff
+
+		seen, newCount, err := collectPosts(ctx, channel, state, cutoff, sink, media, publisher)
+		if err != nil {
+			log.Println(err)
+		}
+		metrics.IncPostsCollected(newCount)
+		if seen {
+			consecutiveSeen++
+		} else {
+			consecutiveSeen = 0
+		}
+
+		remainingTime := time.Until(endTime)
+		log.Printf("[%s] Collected %d unique posts so far. Time left: %02d:%02d",
+			channel, len(state.Seen), int(remainingTime.Minutes()), int(remainingTime.Seconds())%60)
+	}
+
+	log.Printf("[%s] Finished scrolling and collecting unique posts.", channel)
+	return nil
+}
+
+// collectPostsScript extracts the full post envelope from each
+// .tgme_widget_message node: message ID, timestamp, author, text, view
+// count, forward/reply linkage, outbound links, and media descriptors. The
+// returned object keys match Post's JSON tags so chromedp can unmarshal
+// straight into a []Post.
+const collectPostsScript = `
+Array.from(document.querySelectorAll('.tgme_widget_message')).map(el => {
+	const textEl = el.querySelector('.tgme_widget_message_text');
+	const timeEl = el.querySelector('time');
+	const authorEl = el.querySelector('.tgme_widget_message_owner_name, .tgme_widget_message_author_name');
+	const viewsEl = el.querySelector('.tgme_widget_message_views');
+	const forwardEl = el.querySelector('.tgme_widget_message_forwarded_from_name');
+	const replyEl = el.querySelector('.tgme_widget_message_reply');
+	const photoEl = el.querySelector('.tgme_widget_message_photo_wrap');
+	const bgMatch = photoEl ? /url\(['"]?(.*?)['"]?\)/.exec(photoEl.style.backgroundImage) : null;
+
+	return {
+		message_id: el.getAttribute('data-post') || '',
+		timestamp: timeEl ? timeEl.getAttribute('datetime') : '',
+		author: authorEl ? authorEl.textContent.trim() : '',
+		text: textEl ? textEl.innerText : '',
+		views: viewsEl ? viewsEl.textContent.trim() : '',
+		forwarded_from: forwardEl ? (forwardEl.getAttribute('href') || '') : '',
+		reply_to_id: replyEl ? (replyEl.getAttribute('href') || '').split('/').pop() : '',
+		links: textEl ? Array.from(textEl.querySelectorAll('a')).map(a => a.href) : [],
+		photos: bgMatch ? [bgMatch[1]] : [],
+		videos: Array.from(el.querySelectorAll('video')).map(v => v.src).filter(Boolean),
+		documents: Array.from(el.querySelectorAll('.tgme_widget_message_document_title')).map(d => d.textContent.trim()),
+		document_urls: Array.from(el.querySelectorAll('.tgme_widget_message_document')).map(d => d.href || (d.querySelector('a') || {}).href).filter(Boolean),
+		poll_options: Array.from(el.querySelectorAll('.tgme_widget_message_poll_option_text')).map(p => p.textContent.trim()),
+	};
+})
+`
+
+// collectPosts extracts the posts currently loaded in the DOM and processes
+// each one. It reports allSeen (every post in this batch was already present
+// in state, meaning the channel looks caught up) and newCount (how many were
+// new, for the posts_collected metric).
+func collectPosts(ctx context.Context, channel string, state *ChannelState, cutoff sinceCutoff, sink PostSink, media *MediaDownloader, publisher Publisher) (allSeen bool, newCount int, err error) {
+	var posts []Post
+	if err := chromedp.Run(ctx, chromedp.Evaluate(collectPostsScript, &posts)); err != nil {
+		return false, 0, fmt.Errorf("error scraping posts: %w", err)
+	}
+
+	allSeen = len(posts) > 0
+	for _, post := range posts {
+		isNew, err := processPost(channel, post, state, cutoff, sink, media, publisher)
+		if err != nil {
+			log.Println(err)
+		}
+		if isNew {
+			allSeen = false
+			newCount++
+		}
+	}
+	return allSeen, newCount, nil
+}
+
+// processPost records a post as seen and, unless it is already known or
+// excluded by cutoff, downloads its media and writes it to sink. It reports
+// whether the post was new.
+func processPost(channel string, post Post, state *ChannelState, cutoff sinceCutoff, sink PostSink, media *MediaDownloader, publisher Publisher) (bool, error) {
+	key := post.MessageID
+	if key == "" {
+		key = post.Text
+	}
+	if state.Seen[key] {
+		return false, nil
+	}
+	state.MarkSeen(key)
+
+	if cutoff.excludes(post) {
+		return true, nil
+	}
+
+	if media != nil {
+		media.DownloadPost(channel, &post)
+	}
+
+	if err := sink.WritePost(channel, post); err != nil {
+		return true, err
+	}
+
+	if publisher != nil {
+		if err := publisher.Publish(channel, post); err != nil {
+			log.Printf("[%s] error publishing post: %v", channel, err)
+		}
+	}
+
+	log.Printf("[%s] Collected unique post: %s", channel, key)
+	return true, nil
+}
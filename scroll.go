@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	// scrollTimeout bounds how long waitForNewPosts waits, per scroll, for
+	// the mutation observer to see new .tgme_widget_message nodes.
+	scrollTimeout = 6 * time.Second
+	// maxSequentialTimeouts is how many scroll timeouts in a row (rather
+	// than new-node misses due to dedup) abort the channel.
+	maxSequentialTimeouts = 5
+	// scrollBaseBackoff and scrollMaxBackoff bound the exponential backoff
+	// applied between scroll attempts that find nothing new.
+	scrollBaseBackoff = 2 * time.Second
+	scrollMaxBackoff  = 32 * time.Second
+)
+
+// waitForNewPostsScript scrolls the oldest loaded message into view (which
+// triggers Telegram's infinite-scroll fetch of older posts) and resolves
+// once a MutationObserver sees new .tgme_widget_message nodes appear, or
+// after timeoutMs with no new nodes.
+const waitForNewPostsScript = `
+new Promise((resolve) => {
+	const before = document.querySelectorAll('.tgme_widget_message').length;
+	let settled = false;
+	const finish = (found) => {
+		if (settled) return;
+		settled = true;
+		observer.disconnect();
+		resolve(found);
+	};
+	const observer = new MutationObserver(() => {
+		if (document.querySelectorAll('.tgme_widget_message').length > before) finish(true);
+	});
+	observer.observe(document.body, { childList: true, subtree: true });
+	const oldest = document.querySelector('.tgme_widget_message');
+	if (oldest) oldest.scrollIntoView({ block: 'start' });
+	setTimeout(() => finish(false), %d);
+})
+`
+
+// waitForNewPosts triggers a scroll and waits for new posts to load,
+// reporting whether any appeared before scrollTimeout elapsed.
+func waitForNewPosts(ctx context.Context) (bool, error) {
+	script := fmt.Sprintf(waitForNewPostsScript, scrollTimeout.Milliseconds())
+
+	var found bool
+	if err := chromedp.Run(ctx, chromedp.Evaluate(script, &found, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	})); err != nil {
+		return false, fmt.Errorf("error waiting for new posts: %w", err)
+	}
+	return found, nil
+}
+
+// resumeURL appends Telegram's before= pagination parameter so navigation
+// picks up scrolling from lastMessageID instead of the channel root. Used
+// when recreating a tab mid-scrape so a navigation failure doesn't throw
+// away however far into the channel's history the scroll had already
+// reached.
+func resumeURL(url, lastMessageID string) string {
+	seq := messageSeq(lastMessageID)
+	if seq < 0 {
+		return url
+	}
+	return fmt.Sprintf("%s?before=%d", url, seq)
+}
+
+// navigateNewTab opens a fresh chromedp tab derived from allocCtx and
+// navigates it to url, waiting for the first message to render. Used both
+// for the initial navigation and to recover from navigation errors mid-scrape.
+func navigateNewTab(allocCtx context.Context, url, channel string) (context.Context, context.CancelFunc, error) {
+	ctx, cancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+
+	log.Printf("[%s] Navigating to the channel...", channel)
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(url),
+		chromedp.WaitVisible(".tgme_widget_message_text", chromedp.ByQuery),
+	); err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("error navigating to channel: %w", err)
+	}
+	log.Printf("[%s] Successfully navigated to the channel.", channel)
+	return ctx, cancel, nil
+}
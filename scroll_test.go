@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestResumeURL(t *testing.T) {
+	cases := []struct {
+		name          string
+		url           string
+		lastMessageID string
+		want          string
+	}{
+		{"no state yet", "https://t.me/s/channel", "", "https://t.me/s/channel"},
+		{"unparseable message ID", "https://t.me/s/channel", "channel/abc", "https://t.me/s/channel"},
+		{"resumes from last message", "https://t.me/s/channel", "channel/500", "https://t.me/s/channel?before=500"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := resumeURL(c.url, c.lastMessageID); got != c.want {
+				t.Errorf("resumeURL(%q, %q) = %q, want %q", c.url, c.lastMessageID, got, c.want)
+			}
+		})
+	}
+}
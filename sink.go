@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PostSink receives posts as they are collected, decoupling processPost from
+// any particular storage backend.
+type PostSink interface {
+	// WritePost persists a single post collected from the given channel.
+	WritePost(channel string, post Post) error
+	// Close releases any resources held by the sink.
+	Close() error
+}
+
+// csvHeader lists the Post fields written to CSV, in column order.
+var csvHeader = []string{
+	"message_id", "timestamp", "author", "text", "views",
+	"forwarded_from", "reply_to_id", "links", "photos", "videos", "documents", "document_urls", "poll_options",
+}
+
+// FileSink writes each channel's posts to its own file under OutputDir, in
+// one of three formats: a single well-formed JSON array (the default),
+// NDJSON, or CSV.
+type FileSink struct {
+	outputDir string
+	format    string
+
+	mu       sync.Mutex
+	files    map[string]*os.File
+	csvw     map[string]*csv.Writer
+	buffered map[string][]Post
+}
+
+// NewFileSink creates a FileSink rooted at outputDir writing in the given
+// format ("json", "ndjson", or "csv").
+func NewFileSink(outputDir, format string) (*FileSink, error) {
+	switch format {
+	case "json", "ndjson", "csv":
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return &FileSink{
+		outputDir: outputDir,
+		format:    format,
+		files:     make(map[string]*os.File),
+		csvw:      make(map[string]*csv.Writer),
+		buffered:  make(map[string][]Post),
+	}, nil
+}
+
+func (s *FileSink) WritePost(channel string, post Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.format {
+	case "ndjson":
+		file, err := s.fileFor(channel, "ndjson")
+		if err != nil {
+			return err
+		}
+		postData, err := json.Marshal(post)
+		if err != nil {
+			return fmt.Errorf("error marshalling post: %w", err)
+		}
+		if _, err := file.Write(append(postData, '\n')); err != nil {
+			return fmt.Errorf("error writing post to file: %w", err)
+		}
+		return nil
+
+	case "csv":
+		w, isNew, err := s.csvWriterFor(channel)
+		if err != nil {
+			return err
+		}
+		if isNew {
+			if err := w.Write(csvHeader); err != nil {
+				return fmt.Errorf("error writing CSV header: %w", err)
+			}
+		}
+		if err := w.Write(postToCSVRow(post)); err != nil {
+			return fmt.Errorf("error writing CSV row: %w", err)
+		}
+		w.Flush()
+		return w.Error()
+
+	default: // "json": buffered and flushed as a single array on Close.
+		s.buffered[channel] = append(s.buffered[channel], post)
+		return nil
+	}
+}
+
+// fileFor returns the open file for channel, creating it (with the given
+// extension) on first use.
+func (s *FileSink) fileFor(channel, ext string) (*os.File, error) {
+	file, ok := s.files[channel]
+	if ok {
+		return file, nil
+	}
+	filename := createUniqueFilename(channel, ext)
+	filePath := filepath.Join(s.outputDir, filename)
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening/creating output file: %w", err)
+	}
+	s.files[channel] = f
+	return f, nil
+}
+
+// csvWriterFor returns the CSV writer for channel, creating its underlying
+// file on first use. isNew reports whether the writer was just created, so
+// the caller knows to write the header row.
+func (s *FileSink) csvWriterFor(channel string) (w *csv.Writer, isNew bool, err error) {
+	if w, ok := s.csvw[channel]; ok {
+		return w, false, nil
+	}
+	file, err := s.fileFor(channel, "csv")
+	if err != nil {
+		return nil, false, err
+	}
+	w = csv.NewWriter(file)
+	s.csvw[channel] = w
+	return w, true, nil
+}
+
+// postToCSVRow flattens a Post into a CSV row matching csvHeader.
+func postToCSVRow(post Post) []string {
+	return []string{
+		post.MessageID,
+		post.Timestamp,
+		post.Author,
+		post.Text,
+		post.Views,
+		post.ForwardedFrom,
+		post.ReplyToID,
+		strings.Join(post.Links, ";"),
+		strings.Join(post.Photos, ";"),
+		strings.Join(post.Videos, ";"),
+		strings.Join(post.Documents, ";"),
+		strings.Join(post.DocumentURLs, ";"),
+		strings.Join(post.PollOptions, ";"),
+	}
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for channel, posts := range s.buffered {
+		file, err := s.fileFor(channel, "json")
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		data, err := json.MarshalIndent(posts, "", "  ")
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if _, err := file.Write(data); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, f := range s.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// StdoutSink writes every post as a single line of NDJSON to stdout.
+type StdoutSink struct {
+	mu sync.Mutex
+}
+
+// NewStdoutSink creates a StdoutSink.
+func NewStdoutSink() *StdoutSink {
+	return &StdoutSink{}
+}
+
+func (s *StdoutSink) WritePost(channel string, post Post) error {
+	postData, err := json.Marshal(post)
+	if err != nil {
+		return fmt.Errorf("error marshalling post: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = fmt.Println(string(postData))
+	return err
+}
+
+func (s *StdoutSink) Close() error {
+	return nil
+}
+
+// WebhookSink POSTs each post as a JSON body to a configured HTTP endpoint,
+// suitable for S3-compatible ingest proxies or generic webhook receivers.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink creates a WebhookSink that POSTs to url.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *WebhookSink) WritePost(channel string, post Post) error {
+	postData, err := json.Marshal(post)
+	if err != nil {
+		return fmt.Errorf("error marshalling post: %w", err)
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(postData))
+	if err != nil {
+		return fmt.Errorf("error posting to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *WebhookSink) Close() error {
+	return nil
+}
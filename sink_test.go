@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostToCSVRow(t *testing.T) {
+	post := Post{
+		MessageID:     "channel/123",
+		Timestamp:     "2026-07-27T00:00:00Z",
+		Author:        "channel",
+		Text:          "hello",
+		Views:         "42",
+		ForwardedFrom: "https://t.me/s/other",
+		ReplyToID:     "122",
+		Links:         []string{"https://a.example", "https://b.example"},
+		Photos:        []string{"photo1.jpg"},
+		Videos:        nil,
+		Documents:     []string{"doc1"},
+		DocumentURLs:  []string{"https://a.example/doc1"},
+		PollOptions:   []string{"yes", "no"},
+	}
+
+	got := postToCSVRow(post)
+	want := []string{
+		"channel/123",
+		"2026-07-27T00:00:00Z",
+		"channel",
+		"hello",
+		"42",
+		"https://t.me/s/other",
+		"122",
+		"https://a.example;https://b.example",
+		"photo1.jpg",
+		"",
+		"doc1",
+		"https://a.example/doc1",
+		"yes;no",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("postToCSVRow(%+v) = %v, want %v", post, got, want)
+	}
+	if len(got) != len(csvHeader) {
+		t.Errorf("postToCSVRow produced %d fields, want %d to match csvHeader", len(got), len(csvHeader))
+	}
+}
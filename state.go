@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxConsecutiveSeen is how many scroll rounds in a row scrapeChannel
+// tolerates in which every loaded post was already seen before a channel is
+// considered caught up and scrolling stops. Individual already-seen posts
+// are not counted.
+const maxConsecutiveSeen = 5
+
+// ChannelState is the persisted dedup cache for one channel, stored as a
+// JSON manifest under OutputDir/.state so repeated runs resume from the
+// last-seen message instead of rescraping everything.
+type ChannelState struct {
+	LastMessageID string          `json:"last_message_id"`
+	Seen          map[string]bool `json:"seen"`
+}
+
+// newChannelState returns an empty ChannelState.
+func newChannelState() *ChannelState {
+	return &ChannelState{Seen: make(map[string]bool)}
+}
+
+// statePath returns the manifest path for channel under outputDir.
+func statePath(outputDir, channel string) string {
+	return filepath.Join(outputDir, ".state", channel+".json")
+}
+
+// loadChannelState loads the persisted state for channel, returning an empty
+// state if none has been saved yet.
+func loadChannelState(outputDir, channel string) (*ChannelState, error) {
+	data, err := os.ReadFile(statePath(outputDir, channel))
+	if os.IsNotExist(err) {
+		return newChannelState(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state for %s: %w", channel, err)
+	}
+
+	state := newChannelState()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error parsing state for %s: %w", channel, err)
+	}
+	if state.Seen == nil {
+		state.Seen = make(map[string]bool)
+	}
+	return state, nil
+}
+
+// Save writes the channel state to outputDir/.state, creating the directory
+// if necessary.
+func (s *ChannelState) Save(outputDir, channel string) error {
+	dir := filepath.Join(outputDir, ".state")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating state directory: %w", err)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("error marshalling state for %s: %w", channel, err)
+	}
+	if err := os.WriteFile(statePath(outputDir, channel), data, 0644); err != nil {
+		return fmt.Errorf("error writing state for %s: %w", channel, err)
+	}
+	return nil
+}
+
+// MarkSeen records messageID as processed and advances LastMessageID if it
+// is the furthest back in history seen so far. Since scrolling always walks
+// from newest to oldest, that furthest point is the last one collected
+// before a run ends or a tab needs recreating, and is what resuming should
+// pick up from (see resumeURL's before= parameter).
+func (s *ChannelState) MarkSeen(messageID string) {
+	s.Seen[messageID] = true
+	seq := messageSeq(messageID)
+	if seq < 0 {
+		return
+	}
+	if s.LastMessageID == "" || seq < messageSeq(s.LastMessageID) {
+		s.LastMessageID = messageID
+	}
+}
+
+// messageSeq extracts the numeric sequence number from a Telegram data-post
+// value such as "channelname/1234", returning -1 if it can't be parsed.
+func messageSeq(messageID string) int {
+	parts := strings.Split(messageID, "/")
+	seq, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return -1
+	}
+	return seq
+}
+
+// sinceCutoff is the resume point derived from --since: either a minimum
+// message sequence number or a minimum timestamp, whichever the flag value
+// parses as.
+type sinceCutoff struct {
+	seq int
+	t   time.Time
+}
+
+// parseSinceCutoff parses --since, which accepts either a Telegram message
+// ID (e.g. "1234" or "channelname/1234") or an RFC3339 timestamp.
+func parseSinceCutoff(since string) (sinceCutoff, error) {
+	if since == "" {
+		return sinceCutoff{seq: -1}, nil
+	}
+	if seq := messageSeq(since); seq >= 0 {
+		return sinceCutoff{seq: seq}, nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return sinceCutoff{}, fmt.Errorf("error parsing --since %q: %w", since, err)
+	}
+	return sinceCutoff{seq: -1, t: t}, nil
+}
+
+// excludes reports whether post falls before the cutoff and should be
+// skipped rather than written out.
+func (c sinceCutoff) excludes(post Post) bool {
+	if c.seq >= 0 {
+		return messageSeq(post.MessageID) <= c.seq
+	}
+	if !c.t.IsZero() {
+		postTime, err := time.Parse(time.RFC3339, post.Timestamp)
+		return err == nil && !postTime.After(c.t)
+	}
+	return false
+}
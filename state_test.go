@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageSeq(t *testing.T) {
+	cases := []struct {
+		messageID string
+		want      int
+	}{
+		{"channelname/1234", 1234},
+		{"1234", 1234},
+		{"", -1},
+		{"channelname/abc", -1},
+		{"channelname/", -1},
+	}
+	for _, c := range cases {
+		if got := messageSeq(c.messageID); got != c.want {
+			t.Errorf("messageSeq(%q) = %d, want %d", c.messageID, got, c.want)
+		}
+	}
+}
+
+func TestParseSinceCutoff(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		c, err := parseSinceCutoff("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.seq != -1 {
+			t.Errorf("seq = %d, want -1", c.seq)
+		}
+	})
+
+	t.Run("message ID", func(t *testing.T) {
+		c, err := parseSinceCutoff("channelname/500")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.seq != 500 {
+			t.Errorf("seq = %d, want 500", c.seq)
+		}
+	})
+
+	t.Run("RFC3339 timestamp", func(t *testing.T) {
+		c, err := parseSinceCutoff("2026-07-01T00:00:00Z")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.seq != -1 || c.t.IsZero() {
+			t.Errorf("got seq=%d t=%v, want seq=-1 and a parsed timestamp", c.seq, c.t)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := parseSinceCutoff("not-a-cutoff"); err == nil {
+			t.Error("expected an error for an unparseable --since value")
+		}
+	})
+}
+
+func TestSinceCutoffExcludes(t *testing.T) {
+	t.Run("by sequence", func(t *testing.T) {
+		c := sinceCutoff{seq: 100}
+		if !c.excludes(Post{MessageID: "channel/100"}) {
+			t.Error("expected the cutoff message itself to be excluded")
+		}
+		if !c.excludes(Post{MessageID: "channel/50"}) {
+			t.Error("expected an older message to be excluded")
+		}
+		if c.excludes(Post{MessageID: "channel/101"}) {
+			t.Error("expected a newer message to not be excluded")
+		}
+	})
+
+	t.Run("by timestamp", func(t *testing.T) {
+		cutoff, _ := time.Parse(time.RFC3339, "2026-07-01T00:00:00Z")
+		c := sinceCutoff{seq: -1, t: cutoff}
+		if !c.excludes(Post{Timestamp: "2026-06-01T00:00:00Z"}) {
+			t.Error("expected an earlier post to be excluded")
+		}
+		if c.excludes(Post{Timestamp: "2026-08-01T00:00:00Z"}) {
+			t.Error("expected a later post to not be excluded")
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		c := sinceCutoff{seq: -1}
+		if c.excludes(Post{MessageID: "channel/1", Timestamp: "2020-01-01T00:00:00Z"}) {
+			t.Error("expected an unset cutoff to exclude nothing")
+		}
+	})
+}